@@ -0,0 +1,154 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Decoder is implemented by STL decoders that stream Triangle values
+// from an STL file while exposing the metadata carried by the
+// underlying format.
+type Decoder interface {
+	// Decode returns the next Triangle in the STL stream.
+	Decode() (Triangle, error)
+
+	// Name returns the solid name declared by the STL stream, or the
+	// empty string if the underlying format does not carry a name.
+	Name() string
+
+	// Header returns the raw header data declared by the STL stream,
+	// or the empty string if the underlying format does not carry a
+	// header.
+	Header() string
+
+	// NumTriangles returns the number of triangles declared by the STL
+	// stream, or -1 if the underlying format does not declare a count.
+	NumTriangles() int
+}
+
+// NewDecoder returns a Decoder that sniffs r to determine whether it
+// holds ASCII or binary STL data and dispatches to NewTextDecoder or
+// NewBinaryDecoder accordingly.
+//
+// Binary STL files may themselves begin with the ASCII bytes "solid",
+// the same prefix used by the text format, so the prefix alone cannot
+// be trusted. If r is also an io.Seeker, the sniff reads the 80-byte
+// header and 4-byte triangle count and compares the implied file length,
+// 84+n*50, against the actual length of r; when the lengths agree the
+// stream is taken to be binary whatever the header bytes hold, and when
+// they disagree the 84 bytes read are classified by the heuristics
+// described next. Otherwise the first bytes of r are
+// buffered with a bufio.Reader.Peek and classified by the presence of a
+// NUL or other non-printable byte in the header region, or the absence
+// of the "facet normal"/"vertex" keywords that only occur in ASCII STL.
+func NewDecoder(r io.Reader) (Decoder, error) {
+	if s, ok := r.(io.Seeker); ok {
+		bin, err := sniffSeekable(r, s)
+		if err != nil {
+			return nil, err
+		}
+		if bin {
+			dec, err := NewBinaryDecoder(r)
+			if err != nil {
+				return nil, err
+			}
+			return binaryDecoder{dec}, nil
+		}
+		dec, err := NewTextDecoder(r)
+		if err != nil {
+			return nil, err
+		}
+		return textDecoder{dec}, nil
+	}
+
+	br := bufio.NewReaderSize(r, 512)
+	peek, _ := br.Peek(512)
+	if looksBinary(peek) {
+		dec, err := NewBinaryDecoder(br)
+		if err != nil {
+			return nil, err
+		}
+		return binaryDecoder{dec}, nil
+	}
+	dec, err := NewTextDecoder(br)
+	if err != nil {
+		return nil, err
+	}
+	return textDecoder{dec}, nil
+}
+
+// sniffSeekable reports whether the STL stream read from r, which backs
+// the seeker s, is binary. The current offset of s is left unchanged.
+func sniffSeekable(r io.Reader, s io.Seeker) (bin bool, err error) {
+	start, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+	size := end - start
+	_, err = s.Seek(start, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_, seekErr := s.Seek(start, io.SeekStart)
+		if err == nil {
+			err = seekErr
+		}
+	}()
+
+	var buf [84]byte
+	n, err := io.ReadFull(r, buf[:])
+	if err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return looksBinary(buf[:n]), nil
+		}
+		return false, err
+	}
+	count := int64(binary.LittleEndian.Uint32(buf[80:84]))
+	if 84+count*50 == size {
+		return true, nil
+	}
+	return looksBinary(buf[:]), nil
+}
+
+// looksBinary reports whether b, a prefix of an STL stream, appears to
+// hold binary rather than ASCII data.
+func looksBinary(b []byte) bool {
+	head := b
+	if len(head) > 80 {
+		head = head[:80]
+	}
+	for _, c := range head {
+		if c == 0 || c >= 0x7f || (c < 0x20 && c != '\t' && c != '\n' && c != '\r') {
+			return true
+		}
+	}
+	return !bytes.Contains(b, []byte("facet normal")) && !bytes.Contains(b, []byte("vertex"))
+}
+
+// textDecoder adapts *TextDecoder to the Decoder interface.
+type textDecoder struct {
+	*TextDecoder
+}
+
+func (d textDecoder) Name() string      { return d.TextDecoder.Name }
+func (d textDecoder) Header() string    { return "" }
+func (d textDecoder) NumTriangles() int { return -1 }
+
+// binaryDecoder adapts *BinaryDecoder to the Decoder interface.
+type binaryDecoder struct {
+	*BinaryDecoder
+}
+
+func (d binaryDecoder) Name() string   { return "" }
+func (d binaryDecoder) Header() string { return d.BinaryDecoder.Header }
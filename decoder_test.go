@@ -0,0 +1,124 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// onlyReader strips any other interfaces, in particular io.Seeker, from
+// a bytes.Reader so NewDecoder is forced down its non-seekable path.
+type onlyReader struct{ io.Reader }
+
+// binarySTL returns the encoding of n triangles, all the same, as a
+// binary STL file whose 80-byte header begins with the ASCII bytes
+// "solid ", the same prefix that introduces a text STL file.
+func binarySTL(n uint32) []byte {
+	var buf bytes.Buffer
+	var header [80]byte
+	copy(header[:], "solid decoy")
+	buf.Write(header[:])
+	var countBuf [4]byte
+	binary.LittleEndian.PutUint32(countBuf[:], n)
+	buf.Write(countBuf[:])
+	var rec [50]byte
+	putVector(rec[:12], Vector{X: 0, Y: 0, Z: 1})
+	putVector(rec[12:24], Vector{X: 0, Y: 0, Z: 0})
+	putVector(rec[24:36], Vector{X: 1, Y: 0, Z: 0})
+	putVector(rec[36:48], Vector{X: 0, Y: 1, Z: 0})
+	for i := uint32(0); i < n; i++ {
+		buf.Write(rec[:])
+	}
+	return buf.Bytes()
+}
+
+func TestNewDecoderBinaryWithSolidPrefix(t *testing.T) {
+	data := binarySTL(3)
+
+	t.Run("seekable", func(t *testing.T) {
+		dec, err := NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to create decoder: %v", err)
+		}
+		if _, ok := dec.(binaryDecoder); !ok {
+			t.Fatalf("expected binaryDecoder, got %T", dec)
+		}
+		checkBinaryTrap(t, dec)
+	})
+
+	t.Run("non-seekable", func(t *testing.T) {
+		dec, err := NewDecoder(onlyReader{bytes.NewReader(data)})
+		if err != nil {
+			t.Fatalf("failed to create decoder: %v", err)
+		}
+		if _, ok := dec.(binaryDecoder); !ok {
+			t.Fatalf("expected binaryDecoder, got %T", dec)
+		}
+		checkBinaryTrap(t, dec)
+	})
+}
+
+func checkBinaryTrap(t *testing.T, dec Decoder) {
+	t.Helper()
+	if got, want := dec.NumTriangles(), 3; got != want {
+		t.Fatalf("unexpected triangle count: got:%d want:%d", got, want)
+	}
+	n := 0
+	for {
+		tri, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected decode error: %v", err)
+		}
+		want := Vector{X: 0, Y: 0, Z: 1}
+		if tri.Normal != want {
+			t.Errorf("unexpected normal: got:%+v want:%+v", tri.Normal, want)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("unexpected decoded triangle count: got:%d want:%d", n, 3)
+	}
+}
+
+func TestNewDecoderText(t *testing.T) {
+	const ascii = "solid test\n" +
+		"facet normal 0 0 1\n" +
+		"outer loop\n" +
+		"vertex 0 0 0\n" +
+		"vertex 1 0 0\n" +
+		"vertex 0 1 0\n" +
+		"endloop\n" +
+		"endfacet\n" +
+		"endsolid test\n"
+
+	t.Run("seekable", func(t *testing.T) {
+		dec, err := NewDecoder(bytes.NewReader([]byte(ascii)))
+		if err != nil {
+			t.Fatalf("failed to create decoder: %v", err)
+		}
+		if _, ok := dec.(textDecoder); !ok {
+			t.Fatalf("expected textDecoder, got %T", dec)
+		}
+		if got, want := dec.Name(), "test"; got != want {
+			t.Errorf("unexpected name: got:%q want:%q", got, want)
+		}
+	})
+
+	t.Run("non-seekable", func(t *testing.T) {
+		dec, err := NewDecoder(onlyReader{bytes.NewReader([]byte(ascii))})
+		if err != nil {
+			t.Fatalf("failed to create decoder: %v", err)
+		}
+		if _, ok := dec.(textDecoder); !ok {
+			t.Fatalf("expected textDecoder, got %T", dec)
+		}
+	})
+}
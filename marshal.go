@@ -0,0 +1,109 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = Vector{}
+	_ encoding.BinaryUnmarshaler = (*Vector)(nil)
+	_ encoding.TextMarshaler     = Vector{}
+	_ encoding.TextUnmarshaler   = (*Vector)(nil)
+
+	_ encoding.BinaryMarshaler   = Triangle{}
+	_ encoding.BinaryUnmarshaler = (*Triangle)(nil)
+	_ encoding.TextMarshaler     = Triangle{}
+	_ encoding.TextUnmarshaler   = (*Triangle)(nil)
+)
+
+// MarshalBinary returns the 12-byte little-endian float32 encoding of
+// v, the representation used for a vector within a binary STL facet
+// record.
+func (v Vector) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 12)
+	putVector(b, v)
+	return b, nil
+}
+
+// UnmarshalBinary decodes b, which must hold the 12 bytes produced by
+// MarshalBinary, into v.
+func (v *Vector) UnmarshalBinary(b []byte) error {
+	if len(b) != 12 {
+		return fmt.Errorf("stl: invalid vector binary length: %d", len(b))
+	}
+	*v = getVector(b)
+	return nil
+}
+
+// MarshalText returns v as space-separated floating point text, the
+// representation used for an STL vertex or facet normal.
+func (v Vector) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%g %g %g", v.X, v.Y, v.Z)), nil
+}
+
+// UnmarshalText parses b, space-separated floating point text as
+// produced by MarshalText, into v.
+func (v *Vector) UnmarshalText(b []byte) error {
+	got, err := parseVector(b)
+	if err != nil {
+		return err
+	}
+	*v = got
+	return nil
+}
+
+// MarshalBinary returns the 50-byte binary STL facet record encoding of
+// t, the same layout written by BinaryEncoder.
+func (t Triangle) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 50)
+	putVector(b[:12], t.Normal)
+	putVector(b[12:24], t.Vertex[0])
+	putVector(b[24:36], t.Vertex[1])
+	putVector(b[36:48], t.Vertex[2])
+	binary.LittleEndian.PutUint16(b[48:], t.AttrByteCount)
+	return b, nil
+}
+
+// UnmarshalBinary decodes b, which must hold a 50-byte binary STL facet
+// record as produced by MarshalBinary, into t.
+func (t *Triangle) UnmarshalBinary(b []byte) error {
+	if len(b) != 50 {
+		return fmt.Errorf("stl: invalid triangle binary length: %d", len(b))
+	}
+	t.Normal = getVector(b[:12])
+	t.Vertex = [3]Vector{getVector(b[12:24]), getVector(b[24:36]), getVector(b[36:48])}
+	t.AttrByteCount = binary.LittleEndian.Uint16(b[48:])
+	return nil
+}
+
+// MarshalText returns the "facet normal ... endfacet" text encoding of
+// t, as written by TextEncoder but without surrounding indentation.
+func (t Triangle) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf(
+		"facet normal %g %g %g\nouter loop\nvertex %g %g %g\nvertex %g %g %g\nvertex %g %g %g\nendloop\nendfacet\n",
+		t.Normal.X, t.Normal.Y, t.Normal.Z,
+		t.Vertex[0].X, t.Vertex[0].Y, t.Vertex[0].Z,
+		t.Vertex[1].X, t.Vertex[1].Y, t.Vertex[1].Z,
+		t.Vertex[2].X, t.Vertex[2].Y, t.Vertex[2].Z,
+	)), nil
+}
+
+// UnmarshalText parses b, a "facet normal ... endfacet" block as
+// produced by MarshalText, into t.
+func (t *Triangle) UnmarshalText(b []byte) error {
+	dec := TextDecoder{r: bufio.NewReader(bytes.NewReader(b))}
+	got, err := dec.Decode()
+	if err != nil {
+		return err
+	}
+	*t = got
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import "testing"
+
+func TestVectorBinaryRoundTrip(t *testing.T) {
+	want := Vector{1.5, -2.25, 3}
+	b, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var got Vector
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected vector: got:%+v want:%+v", got, want)
+	}
+}
+
+func TestVectorTextRoundTrip(t *testing.T) {
+	want := Vector{1.5, -2.25, 3}
+	b, err := want.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var got Vector
+	err = got.UnmarshalText(b)
+	if err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected vector: got:%+v want:%+v", got, want)
+	}
+}
+
+func TestTriangleBinaryRoundTrip(t *testing.T) {
+	want := Triangle{
+		Normal:        Vector{0, 0, 1},
+		Vertex:        [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		AttrByteCount: 0xffff,
+	}
+	b, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var got Triangle
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if !sameTriangle(got, want, 0) || got.AttrByteCount != want.AttrByteCount {
+		t.Errorf("unexpected triangle: got:%+v want:%+v", got, want)
+	}
+}
+
+func TestTriangleTextRoundTrip(t *testing.T) {
+	want := Triangle{
+		Normal: Vector{0, 0, 1},
+		Vertex: [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+	}
+	b, err := want.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var got Triangle
+	err = got.UnmarshalText(b)
+	if err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if !sameTriangle(got, want, 0) {
+		t.Errorf("unexpected triangle: got:%+v want:%+v", got, want)
+	}
+}
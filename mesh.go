@@ -0,0 +1,251 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Encoder is implemented by STL encoders that accept a stream of
+// Triangle values, such as *TextEncoder and *BinaryEncoder.
+type Encoder interface {
+	// Encode encodes t into the STL stream.
+	Encode(t Triangle) error
+}
+
+// Format identifies an STL encoding.
+type Format int
+
+const (
+	// Text is the ASCII STL encoding.
+	Text Format = iota
+	// Binary is the binary STL encoding.
+	Binary
+)
+
+// Mesh is an indexed triangle mesh: a deduplicated set of vertices
+// together with triangle indices into that set. It is the repaired,
+// welded counterpart to the triangle soup produced directly by a
+// Decoder.
+type Mesh struct {
+	// Vertices holds the unique, welded vertex positions.
+	Vertices []Vector
+
+	// Indices holds the triangle vertex indices into Vertices, one
+	// triple per triangle.
+	Indices [][3]uint32
+
+	// Normals holds the stored facet normal for each triangle, indexed
+	// in parallel with Indices.
+	Normals []Vector
+}
+
+// WeldEpsilon is the default weld tolerance, in model units, used by
+// ReadMesh: the size of the grid cells within which vertices are merged.
+const WeldEpsilon = 1e-6
+
+// ReadMesh reads an STL stream from r, auto-detecting ASCII or binary
+// encoding via NewDecoder, and welds coincident vertices into an
+// indexed Mesh using WeldEpsilon as the merge tolerance. Call Weld to
+// re-weld the result with a different tolerance.
+func ReadMesh(r io.Reader) (*Mesh, error) {
+	dec, err := NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	var tris []Triangle
+	for {
+		t, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tris = append(tris, t)
+	}
+	return weldTriangles(tris, WeldEpsilon), nil
+}
+
+// Weld rebuilds m in place, merging vertices whose quantized positions,
+// floor(coord/eps), are identical, and returns m. Vertices closer than
+// eps that lie on opposite sides of a grid cell boundary are not merged.
+func (m *Mesh) Weld(eps float64) *Mesh {
+	tris := make([]Triangle, len(m.Indices))
+	for i, idx := range m.Indices {
+		tris[i] = Triangle{
+			Normal: m.Normals[i],
+			Vertex: [3]Vector{m.Vertices[idx[0]], m.Vertices[idx[1]], m.Vertices[idx[2]]},
+		}
+	}
+	*m = *weldTriangles(tris, eps)
+	return m
+}
+
+// weldTriangles builds an indexed Mesh from tris, merging vertices
+// whose quantized position, floor(coord/eps), is identical.
+func weldTriangles(tris []Triangle, eps float64) *Mesh {
+	type key [3]int64
+	index := make(map[key]uint32)
+	m := &Mesh{
+		Indices: make([][3]uint32, len(tris)),
+		Normals: make([]Vector, len(tris)),
+	}
+	quantize := func(v Vector) key {
+		return key{
+			int64(math.Floor(v.X / eps)),
+			int64(math.Floor(v.Y / eps)),
+			int64(math.Floor(v.Z / eps)),
+		}
+	}
+	for i, t := range tris {
+		m.Normals[i] = t.Normal
+		for j, v := range t.Vertex {
+			k := quantize(v)
+			idx, ok := index[k]
+			if !ok {
+				idx = uint32(len(m.Vertices))
+				index[k] = idx
+				m.Vertices = append(m.Vertices, v)
+			}
+			m.Indices[i][j] = idx
+		}
+	}
+	return m
+}
+
+// Write writes m to w in the given format.
+func (m *Mesh) Write(w io.Writer, format Format) error {
+	switch format {
+	case Binary:
+		enc, err := NewBinaryEncoder(w, "", uint32(len(m.Indices)))
+		if err != nil {
+			return err
+		}
+		return m.encodeTo(enc)
+	case Text:
+		enc, err := NewTextEncoder(w, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = m.encodeTo(enc)
+		if err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("stl: unknown format: %d", format)
+	}
+}
+
+func (m *Mesh) encodeTo(enc Encoder) error {
+	for i, idx := range m.Indices {
+		err := enc.Encode(Triangle{
+			Normal: m.Normals[i],
+			Vertex: [3]Vector{m.Vertices[idx[0]], m.Vertices[idx[1]], m.Vertices[idx[2]]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Edge is an undirected edge between two vertex indices, with the
+// lower index first.
+type Edge [2]uint32
+
+// ValidationReport describes the geometric issues found by Mesh.Validate.
+type ValidationReport struct {
+	// NonManifoldEdges lists edges that are shared by a number of
+	// triangles other than two.
+	NonManifoldEdges []Edge
+
+	// DegenerateTriangles lists the indices of triangles with zero
+	// area.
+	DegenerateTriangles []int
+
+	// InconsistentWinding lists the indices of triangles that share an
+	// edge with a neighbour in the same direction, rather than the
+	// reverse direction required for consistent winding.
+	InconsistentWinding []int
+
+	// NormalMismatch lists the indices of triangles whose stored
+	// Normal disagrees with the geometric facet normal beyond the
+	// tolerance passed to Validate.
+	NormalMismatch []int
+}
+
+// Validate checks m for the defects typical of STL files: non-manifold
+// edges, degenerate (zero-area) triangles, inconsistent winding order,
+// and stored normals that disagree with the geometric facet normal by
+// more than tol.
+func (m *Mesh) Validate(tol float64) ValidationReport {
+	var report ValidationReport
+
+	edgeCount := make(map[Edge]int)
+	dirCount := make(map[[2]uint32]int)
+	for i, idx := range m.Indices {
+		v0, v1, v2 := m.Vertices[idx[0]], m.Vertices[idx[1]], m.Vertices[idx[2]]
+		cross := v1.sub(v0).cross(v2.sub(v0))
+		area := cross.length() / 2
+		if area == 0 || math.IsNaN(area) {
+			report.DegenerateTriangles = append(report.DegenerateTriangles, i)
+		} else if i < len(m.Normals) {
+			n := cross.scale(1 / cross.length())
+			if !closeVector(n, m.Normals[i], tol) {
+				report.NormalMismatch = append(report.NormalMismatch, i)
+			}
+		}
+
+		edges := [3][2]uint32{{idx[0], idx[1]}, {idx[1], idx[2]}, {idx[2], idx[0]}}
+		for _, e := range edges {
+			edgeCount[canonicalEdge(e[0], e[1])]++
+			dirCount[e]++
+		}
+	}
+
+	for e, c := range edgeCount {
+		if c != 2 {
+			report.NonManifoldEdges = append(report.NonManifoldEdges, e)
+		}
+	}
+	// Map iteration order is randomized, but the rest of the report is
+	// built by ranging over m.Indices in order, so sort this field too
+	// to keep the report deterministic.
+	sort.Slice(report.NonManifoldEdges, func(i, j int) bool {
+		a, b := report.NonManifoldEdges[i], report.NonManifoldEdges[j]
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		return a[1] < b[1]
+	})
+
+	for i, idx := range m.Indices {
+		edges := [3][2]uint32{{idx[0], idx[1]}, {idx[1], idx[2]}, {idx[2], idx[0]}}
+		for _, e := range edges {
+			if dirCount[e] > 1 {
+				report.InconsistentWinding = append(report.InconsistentWinding, i)
+				break
+			}
+		}
+	}
+
+	return report
+}
+
+func canonicalEdge(a, b uint32) Edge {
+	if a < b {
+		return Edge{a, b}
+	}
+	return Edge{b, a}
+}
+
+func closeVector(a, b Vector, tol float64) bool {
+	return math.Abs(a.X-b.X) <= tol && math.Abs(a.Y-b.Y) <= tol && math.Abs(a.Z-b.Z) <= tol
+}
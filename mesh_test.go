@@ -0,0 +1,95 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWeld(t *testing.T) {
+	tris := []Triangle{
+		{
+			Normal: Vector{0, 0, 1},
+			Vertex: [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		},
+		{
+			// Shares two vertices with the first triangle exactly, and
+			// a third that is near the origin, just outside
+			// WeldEpsilon but within a coarser tolerance.
+			Normal: Vector{0, 0, 1},
+			Vertex: [3]Vector{{1, 0, 0}, {0, 1, 0}, {1e-4, 1e-4, 0}},
+		},
+	}
+
+	m := weldTriangles(tris, WeldEpsilon)
+	if len(m.Vertices) != 4 {
+		t.Fatalf("unexpected vertex count at tight tolerance: got:%d want:4 vertices:%+v", len(m.Vertices), m.Vertices)
+	}
+	if m.Indices[0][1] != m.Indices[1][0] || m.Indices[0][2] != m.Indices[1][1] {
+		t.Errorf("shared vertices were not welded: indices:%+v", m.Indices)
+	}
+
+	m.Weld(1e-3)
+	if len(m.Vertices) != 3 {
+		t.Errorf("unexpected vertex count after coarse re-weld: got:%d want:3 vertices:%+v", len(m.Vertices), m.Vertices)
+	}
+}
+
+func TestValidateDegenerateTriangles(t *testing.T) {
+	m := &Mesh{
+		Vertices: []Vector{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}},
+		Indices:  [][3]uint32{{0, 1, 2}},
+		Normals:  []Vector{{0, 0, 1}},
+	}
+	report := m.Validate(1e-9)
+	if !reflect.DeepEqual(report.DegenerateTriangles, []int{0}) {
+		t.Errorf("unexpected degenerate triangles: got:%v want:[0]", report.DegenerateTriangles)
+	}
+}
+
+func TestValidateNormalMismatch(t *testing.T) {
+	m := &Mesh{
+		Vertices: []Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		Indices:  [][3]uint32{{0, 1, 2}},
+		Normals:  []Vector{{1, 0, 0}}, // geometric normal is {0, 0, 1}
+	}
+	report := m.Validate(1e-9)
+	if !reflect.DeepEqual(report.NormalMismatch, []int{0}) {
+		t.Errorf("unexpected normal mismatches: got:%v want:[0]", report.NormalMismatch)
+	}
+}
+
+func TestValidateInconsistentWinding(t *testing.T) {
+	// Two triangles that both traverse the shared edge {0, 1} in the
+	// same direction, rather than the reverse required for consistent
+	// winding.
+	m := &Mesh{
+		Vertices: []Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+		Indices:  [][3]uint32{{0, 1, 2}, {0, 1, 3}},
+		Normals:  []Vector{{0, 0, 1}, {0, -1, 0}},
+	}
+	report := m.Validate(1e-9)
+	if !reflect.DeepEqual(report.InconsistentWinding, []int{0, 1}) {
+		t.Errorf("unexpected inconsistent winding: got:%v want:[0 1]", report.InconsistentWinding)
+	}
+}
+
+func TestValidateNonManifoldEdges(t *testing.T) {
+	// A single triangle has three edges, each used only once, so all
+	// three are non-manifold.
+	m := &Mesh{
+		Vertices: []Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		Indices:  [][3]uint32{{0, 1, 2}},
+		Normals:  []Vector{{0, 0, 1}},
+	}
+	want := []Edge{{0, 1}, {0, 2}, {1, 2}}
+	for i := 0; i < 5; i++ {
+		report := m.Validate(1e-9)
+		if !reflect.DeepEqual(report.NonManifoldEdges, want) {
+			t.Fatalf("unexpected non-manifold edges on run %d: got:%v want:%v", i, report.NonManifoldEdges, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	binaryHeaderLen = 80
+	binaryRecordLen = 50
+)
+
+// ParallelBinaryDecoder decodes a binary STL stream held in r using
+// multiple goroutines, exploiting the format's fixed 50-byte triangle
+// record layout to split the work into contiguous ranges read
+// independently via io.SectionReader.
+type ParallelBinaryDecoder struct {
+	r       io.ReaderAt
+	header  string
+	n       int64
+	workers int
+}
+
+// NewParallelBinaryDecoder returns a decoder for the binary STL stream
+// held in r, which has the given total size. Decoding is split across
+// workers goroutines; if workers is less than 1, a single worker is
+// used.
+func NewParallelBinaryDecoder(r io.ReaderAt, size int64, workers int) (*ParallelBinaryDecoder, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	var buf [binaryHeaderLen + 4]byte
+	_, err := io.ReadFull(io.NewSectionReader(r, 0, int64(len(buf))), buf[:])
+	if err != nil {
+		return nil, err
+	}
+	n := int64(binary.LittleEndian.Uint32(buf[binaryHeaderLen:]))
+	want := int64(binaryHeaderLen+4) + n*binaryRecordLen
+	if want != size {
+		return nil, fmt.Errorf("stl: declared triangle count disagrees with file size: got:%d want:%d", size, want)
+	}
+	return &ParallelBinaryDecoder{
+		r:       r,
+		header:  string(buf[:binaryHeaderLen]),
+		n:       n,
+		workers: workers,
+	}, nil
+}
+
+// Header returns the STL header data.
+func (dec *ParallelBinaryDecoder) Header() string { return dec.header }
+
+// NumTriangles returns the number of triangles encoded in the STL
+// stream.
+func (dec *ParallelBinaryDecoder) NumTriangles() int { return int(dec.n) }
+
+// workerRange is a contiguous, half-open range of triangle indices
+// assigned to one worker.
+type workerRange struct{ lo, hi int64 }
+
+// workerRanges splits the n triangles into up to workers contiguous,
+// roughly equal ranges, omitting any that would be empty.
+func workerRanges(n int64, workers int) []workerRange {
+	if int64(workers) > n {
+		workers = int(n)
+	}
+	if workers < 1 {
+		return nil
+	}
+	chunk := (n + int64(workers) - 1) / int64(workers)
+	ranges := make([]workerRange, 0, workers)
+	for lo := int64(0); lo < n; lo += chunk {
+		hi := lo + chunk
+		if hi > n {
+			hi = n
+		}
+		ranges = append(ranges, workerRange{lo, hi})
+	}
+	return ranges
+}
+
+// decodeRange decodes the triangles in [lo, hi) of the stream, reading
+// the whole range in a single io.ReadFull call to avoid the per-record
+// allocation used by BinaryDecoder.Decode.
+func (dec *ParallelBinaryDecoder) decodeRange(lo, hi int64) ([]Triangle, error) {
+	off := int64(binaryHeaderLen+4) + lo*binaryRecordLen
+	buf := make([]byte, (hi-lo)*binaryRecordLen)
+	_, err := io.ReadFull(io.NewSectionReader(dec.r, off, int64(len(buf))), buf)
+	if err != nil {
+		return nil, err
+	}
+	tris := make([]Triangle, hi-lo)
+	for i := range tris {
+		b := buf[i*binaryRecordLen : (i+1)*binaryRecordLen]
+		tris[i] = Triangle{
+			Normal: getVector(b[:12]),
+			Vertex: [3]Vector{
+				getVector(b[12:24]),
+				getVector(b[24:36]),
+				getVector(b[36:48]),
+			},
+			AttrByteCount: binary.LittleEndian.Uint16(b[48:]),
+		}
+	}
+	return tris, nil
+}
+
+// DecodeAll decodes every triangle in the stream, in file order, using
+// dec.workers goroutines each decoding a disjoint range into a buffer of
+// its own. Each buffer is then copied into its place in the result
+// slice; triangles are not decoded in place.
+func (dec *ParallelBinaryDecoder) DecodeAll() ([]Triangle, error) {
+	out := make([]Triangle, dec.n)
+	ranges := workerRanges(dec.n, dec.workers)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(ranges))
+	for i, rg := range ranges {
+		wg.Add(1)
+		go func(i int, rg workerRange) {
+			defer wg.Done()
+			tris, err := dec.decodeRange(rg.lo, rg.hi)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			copy(out[rg.lo:rg.hi], tris)
+		}(i, rg)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Triangles returns a channel that yields every triangle in the stream,
+// in file order, and a channel that receives the first error
+// encountered, if any. Each worker decodes its range concurrently into
+// its own buffer; the channel is fed by draining those buffers in range
+// order so the output preserves file order even though decoding does
+// not. The triangle channel is closed once all triangles have been
+// sent, or early if a worker fails to read its range; in the latter
+// case the error channel receives the failure before it is closed, so
+// callers can distinguish a short read from a mesh that genuinely has
+// fewer triangles than NumTriangles.
+func (dec *ParallelBinaryDecoder) Triangles() (<-chan Triangle, <-chan error) {
+	out := make(chan Triangle)
+	errc := make(chan error, 1)
+	ranges := workerRanges(dec.n, dec.workers)
+
+	results := make([]chan []Triangle, len(ranges))
+	for i := range results {
+		results[i] = make(chan []Triangle, 1)
+	}
+	errs := make([]error, len(ranges))
+	for i, rg := range ranges {
+		go func(i int, rg workerRange) {
+			tris, err := dec.decodeRange(rg.lo, rg.hi)
+			if err != nil {
+				errs[i] = err
+				close(results[i])
+				return
+			}
+			results[i] <- tris
+		}(i, rg)
+	}
+
+	go func() {
+		defer close(out)
+		defer close(errc)
+		for i, c := range results {
+			tris, ok := <-c
+			if !ok {
+				errc <- errs[i]
+				return
+			}
+			for _, t := range tris {
+				out <- t
+			}
+		}
+	}()
+	return out, errc
+}
@@ -0,0 +1,121 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+var workerRangesTests = []struct {
+	n       int64
+	workers int
+	want    []workerRange
+}{
+	{n: 0, workers: 3, want: nil},
+	{n: 7, workers: 1, want: []workerRange{{0, 7}}},
+	{n: 7, workers: 3, want: []workerRange{{0, 3}, {3, 6}, {6, 7}}},
+	{n: 2, workers: 5, want: []workerRange{{0, 1}, {1, 2}}},
+	{n: 9, workers: 3, want: []workerRange{{0, 3}, {3, 6}, {6, 9}}},
+}
+
+func TestWorkerRanges(t *testing.T) {
+	for _, test := range workerRangesTests {
+		got := workerRanges(test.n, test.workers)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected ranges for n:%d workers:%d: got:%v want:%v", test.n, test.workers, got, test.want)
+		}
+	}
+}
+
+// binaryParallelSTL returns the encoding of n triangles as a binary STL
+// file, each triangle distinguishable by index so that decode order can
+// be checked.
+func binaryParallelSTL(n uint32) []byte {
+	var buf bytes.Buffer
+	var header [80]byte
+	buf.Write(header[:])
+	var countBuf [4]byte
+	binary.LittleEndian.PutUint32(countBuf[:], n)
+	buf.Write(countBuf[:])
+	for i := uint32(0); i < n; i++ {
+		var rec [50]byte
+		putVector(rec[:12], Vector{0, 0, 1})
+		putVector(rec[12:24], Vector{float64(i), 0, 0})
+		putVector(rec[24:36], Vector{float64(i) + 1, 0, 0})
+		putVector(rec[36:48], Vector{float64(i), 1, 0})
+		buf.Write(rec[:])
+	}
+	return buf.Bytes()
+}
+
+func TestNewParallelBinaryDecoderSizeMismatch(t *testing.T) {
+	data := binaryParallelSTL(2)
+	_, err := NewParallelBinaryDecoder(bytes.NewReader(data), int64(len(data))+1, 2)
+	if err == nil {
+		t.Fatal("expected error for declared triangle count disagreeing with file size")
+	}
+}
+
+func TestParallelDecodeAllAndTrianglesAgree(t *testing.T) {
+	const n = 7 // not a multiple of 3, so a 3-worker split is uneven
+	data := binaryParallelSTL(n)
+
+	dec, err := NewParallelBinaryDecoder(bytes.NewReader(data), int64(len(data)), 3)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+
+	all, err := dec.DecodeAll()
+	if err != nil {
+		t.Fatalf("DecodeAll failed: %v", err)
+	}
+	if len(all) != n {
+		t.Fatalf("unexpected triangle count from DecodeAll: got:%d want:%d", len(all), n)
+	}
+
+	ch, errc := dec.Triangles()
+	var streamed []Triangle
+	for tri := range ch {
+		streamed = append(streamed, tri)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Triangles reported unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(streamed, all) {
+		t.Errorf("Triangles disagrees with DecodeAll:\nstreamed:%+v\nall:%+v", streamed, all)
+	}
+	for i, tri := range streamed {
+		if want := float64(i); tri.Vertex[0].X != want {
+			t.Errorf("unexpected triangle %d out of order: got X:%v want:%v", i, tri.Vertex[0].X, want)
+		}
+	}
+}
+
+func TestParallelTrianglesReportsReadError(t *testing.T) {
+	const n = 4
+	data := binaryParallelSTL(n)
+	// Truncate the file after the header so every worker's read fails,
+	// while keeping NewParallelBinaryDecoder's own size check satisfied
+	// by lying about the reader's size.
+	truncated := data[:binaryHeaderLen+4]
+
+	dec, err := NewParallelBinaryDecoder(bytes.NewReader(data), int64(len(data)), 2)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	// Swap in a reader that is too short once decoding begins.
+	dec.r = bytes.NewReader(truncated)
+
+	ch, errc := dec.Triangles()
+	for range ch {
+	}
+	if err := <-errc; err == nil {
+		t.Error("expected an error from Triangles after a short read")
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package threemf implements reading and writing of the 3D
+// Manufacturing Format (3MF), a ZIP archive holding an XML description
+// of a mesh, as stl.Mesh values.
+package threemf
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/kortschak/stl"
+)
+
+// modelPart is the path, within the archive, of the 3MF part that
+// describes the model.
+const modelPart = "3D/3dmodel.model"
+
+// unitToMillimeter gives the scale factor from a 3MF unit name to
+// millimeters.
+var unitToMillimeter = map[string]float64{
+	"micron":     0.001,
+	"millimeter": 1,
+	"centimeter": 10,
+	"inch":       25.4,
+	"foot":       304.8,
+	"meter":      1000,
+}
+
+// Decode reads the 3MF archive in r, which has the given total size,
+// and returns its geometry as an stl.Mesh. Only objects referenced by a
+// <build> item are included, once for each item that references them.
+// An item's placement transform, composed with its object's own
+// <components> transform if present, and the archive's declared unit
+// are applied to the vertex positions so the result is expressed in
+// millimeters, matching the unitless convention of STL.
+func Decode(r io.ReaderAt, size int64) (*stl.Mesh, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var part *zip.File
+	for _, f := range zr.File {
+		if f.Name == modelPart {
+			part = f
+			break
+		}
+	}
+	if part == nil {
+		return nil, fmt.Errorf("threemf: archive has no %s part", modelPart)
+	}
+	rc, err := part.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc model
+	err = xml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("threemf: %w", err)
+	}
+
+	scale, ok := unitToMillimeter[doc.Unit]
+	if !ok {
+		scale = 1
+	}
+
+	objects := make(map[string]*object, len(doc.Resources.Objects))
+	for i := range doc.Resources.Objects {
+		obj := &doc.Resources.Objects[i]
+		objects[obj.ID] = obj
+	}
+
+	m := &stl.Mesh{}
+	for _, it := range doc.Build.Items {
+		obj, ok := objects[it.ObjectID]
+		if !ok {
+			return nil, fmt.Errorf("threemf: build item references unknown object %q", it.ObjectID)
+		}
+
+		xf := identityTransform
+		if obj.Components.Transform != "" {
+			xf, err = parseTransform(obj.Components.Transform)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if it.Transform != "" {
+			itemXf, err := parseTransform(it.Transform)
+			if err != nil {
+				return nil, err
+			}
+			xf = itemXf.compose(xf)
+		}
+
+		base := uint32(len(m.Vertices))
+		for _, v := range obj.Mesh.Vertices.Vertex {
+			p := xf.apply(stl.Vector{X: v.X, Y: v.Y, Z: v.Z})
+			m.Vertices = append(m.Vertices, stl.Vector{X: p.X * scale, Y: p.Y * scale, Z: p.Z * scale})
+		}
+		nv := uint32(len(obj.Mesh.Vertices.Vertex))
+		for _, tr := range obj.Mesh.Triangles.Triangle {
+			if tr.V1 >= nv || tr.V2 >= nv || tr.V3 >= nv {
+				return nil, fmt.Errorf("threemf: triangle references out-of-range vertex: %+v", tr)
+			}
+			idx := [3]uint32{base + tr.V1, base + tr.V2, base + tr.V3}
+			m.Indices = append(m.Indices, idx)
+			t := stl.Triangle{Vertex: [3]stl.Vector{m.Vertices[idx[0]], m.Vertices[idx[1]], m.Vertices[idx[2]]}}
+			m.Normals = append(m.Normals, t.FacetNormal())
+		}
+	}
+	return m, nil
+}
+
+// Encode writes m to w as a 3MF archive with vertex positions taken to
+// already be in millimeters.
+func Encode(w io.Writer, m *stl.Mesh) error {
+	zw := zip.NewWriter(w)
+
+	err := writeContentTypes(zw)
+	if err != nil {
+		return err
+	}
+	err = writeRels(zw)
+	if err != nil {
+		return err
+	}
+	err = writeModel(zw, m)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeContentTypes(zw *zip.Writer) error {
+	f, err := zw.Create("[Content_Types].xml")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, xml.Header+`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`+
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`+
+		`<Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>`+
+		`</Types>`)
+	return err
+}
+
+func writeRels(zw *zip.Writer) error {
+	f, err := zw.Create("_rels/.rels")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, xml.Header+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Target="/3D/3dmodel.model" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>`+
+		`</Relationships>`)
+	return err
+}
+
+func writeModel(zw *zip.Writer, m *stl.Mesh) error {
+	f, err := zw.Create(modelPart)
+	if err != nil {
+		return err
+	}
+
+	doc := model{
+		Unit: "millimeter",
+		Resources: resources{
+			Objects: []object{{
+				ID:   "1",
+				Type: "model",
+				Mesh: meshXML{
+					Vertices:  verticesXML{Vertex: make([]vertexXML, len(m.Vertices))},
+					Triangles: trianglesXML{Triangle: make([]triangleXML, len(m.Indices))},
+				},
+			}},
+		},
+		Build: build{Items: []item{{ObjectID: "1"}}},
+	}
+	for i, v := range m.Vertices {
+		doc.Resources.Objects[0].Mesh.Vertices.Vertex[i] = vertexXML{X: v.X, Y: v.Y, Z: v.Z}
+	}
+	for i, idx := range m.Indices {
+		doc.Resources.Objects[0].Mesh.Triangles.Triangle[i] = triangleXML{V1: idx[0], V2: idx[1], V3: idx[2]}
+	}
+
+	_, err = io.WriteString(f, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// model is the root element of a 3MF 3dmodel.model part.
+type model struct {
+	XMLName   xml.Name  `xml:"http://schemas.microsoft.com/3dmanufacturing/core/2015/02 model"`
+	Unit      string    `xml:"unit,attr"`
+	Resources resources `xml:"resources"`
+	Build     build     `xml:"build"`
+}
+
+type resources struct {
+	Objects []object `xml:"object"`
+}
+
+type object struct {
+	ID   string  `xml:"id,attr"`
+	Type string  `xml:"type,attr,omitempty"`
+	Mesh meshXML `xml:"mesh"`
+
+	Components components `xml:"components"`
+}
+
+type components struct {
+	Transform string `xml:"transform,attr,omitempty"`
+}
+
+type meshXML struct {
+	Vertices  verticesXML  `xml:"vertices"`
+	Triangles trianglesXML `xml:"triangles"`
+}
+
+type verticesXML struct {
+	Vertex []vertexXML `xml:"vertex"`
+}
+
+type vertexXML struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+type trianglesXML struct {
+	Triangle []triangleXML `xml:"triangle"`
+}
+
+type triangleXML struct {
+	V1 uint32 `xml:"v1,attr"`
+	V2 uint32 `xml:"v2,attr"`
+	V3 uint32 `xml:"v3,attr"`
+}
+
+type build struct {
+	Items []item `xml:"item"`
+}
+
+type item struct {
+	ObjectID  string `xml:"objectid,attr"`
+	Transform string `xml:"transform,attr,omitempty"`
+}
+
+// transform is a 3MF object transform: a row-major 4×3 affine matrix
+// applied to row vectors, the last column of an implicit 4×4 matrix
+// being {0,0,0,1}.
+type transform [4][3]float64
+
+var identityTransform = transform{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {0, 0, 0}}
+
+// parseTransform parses s, a 3MF "transform" attribute: twelve
+// space-separated floats m11 m12 m13 m21 m22 m23 m31 m32 m33 m41 m42 m43.
+func parseTransform(s string) (transform, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 12 {
+		return transform{}, fmt.Errorf("threemf: invalid transform: %q", s)
+	}
+	var vals [12]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return transform{}, fmt.Errorf("threemf: invalid transform: %q", s)
+		}
+		vals[i] = v
+	}
+	return transform{
+		{vals[0], vals[1], vals[2]},
+		{vals[3], vals[4], vals[5]},
+		{vals[6], vals[7], vals[8]},
+		{vals[9], vals[10], vals[11]},
+	}, nil
+}
+
+// apply applies t to v as an affine transform.
+func (t transform) apply(v stl.Vector) stl.Vector {
+	return stl.Vector{
+		X: v.X*t[0][0] + v.Y*t[1][0] + v.Z*t[2][0] + t[3][0],
+		Y: v.X*t[0][1] + v.Y*t[1][1] + v.Z*t[2][1] + t[3][1],
+		Z: v.X*t[0][2] + v.Y*t[1][2] + v.Z*t[2][2] + t[3][2],
+	}
+}
+
+// compose returns the transform that applies inner first, then t, i.e.
+// compose(v) == t.apply(inner.apply(v)).
+func (t transform) compose(inner transform) transform {
+	var out transform
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			var sum float64
+			for k := 0; k < 3; k++ {
+				sum += inner[i][k] * t[k][j]
+			}
+			out[i][j] = sum
+		}
+	}
+	for j := 0; j < 3; j++ {
+		var sum float64
+		for k := 0; k < 3; k++ {
+			sum += inner[3][k] * t[k][j]
+		}
+		out[3][j] = sum + t[3][j]
+	}
+	return out
+}
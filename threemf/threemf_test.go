@@ -0,0 +1,155 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package threemf
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kortschak/stl"
+)
+
+func TestRoundTrip(t *testing.T) {
+	want := &stl.Mesh{
+		Vertices: []stl.Vector{
+			{X: 0, Y: 0, Z: 0},
+			{X: 1, Y: 0, Z: 0},
+			{X: 0, Y: 1, Z: 0},
+			{X: 0, Y: 0, Z: 1},
+		},
+		Indices: [][3]uint32{
+			{0, 1, 2},
+			{0, 1, 3},
+		},
+		Normals: []stl.Vector{
+			{X: 0, Y: 0, Z: 1},
+			{X: 0, Y: -1, Z: 0},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, want)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	got, err := Decode(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	if len(got.Vertices) != len(want.Vertices) {
+		t.Fatalf("unexpected vertex count: got:%d want:%d", len(got.Vertices), len(want.Vertices))
+	}
+	for i, v := range want.Vertices {
+		if got.Vertices[i] != v {
+			t.Errorf("unexpected vertex %d: got:%+v want:%+v", i, got.Vertices[i], v)
+		}
+	}
+	if len(got.Indices) != len(want.Indices) {
+		t.Fatalf("unexpected triangle count: got:%d want:%d", len(got.Indices), len(want.Indices))
+	}
+	for i, idx := range want.Indices {
+		if got.Indices[i] != idx {
+			t.Errorf("unexpected indices %d: got:%+v want:%+v", i, got.Indices[i], idx)
+		}
+	}
+}
+
+// TestDecodeBuildItemTransform checks that Decode applies a <build>
+// item's placement transform, the mechanism a real slicer or CAD tool
+// uses to position an object, rather than only the <components>
+// transform used to compose sub-objects.
+func TestDecodeBuildItemTransform(t *testing.T) {
+	const modelXML = xmlHeader +
+		`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" unit="millimeter">` +
+		`<resources>` +
+		`<object id="1" type="model">` +
+		`<mesh>` +
+		`<vertices>` +
+		`<vertex x="0" y="0" z="0"/>` +
+		`<vertex x="1" y="0" z="0"/>` +
+		`<vertex x="0" y="1" z="0"/>` +
+		`</vertices>` +
+		`<triangles><triangle v1="0" v2="1" v3="2"/></triangles>` +
+		`</mesh>` +
+		`</object>` +
+		`</resources>` +
+		`<build><item objectid="1" transform="1 0 0 0 1 0 0 0 1 10 0 0"/></build>` +
+		`</model>`
+
+	archive := archiveWithModel(t, modelXML)
+	got, err := Decode(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	want := []stl.Vector{
+		{X: 10, Y: 0, Z: 0},
+		{X: 11, Y: 0, Z: 0},
+		{X: 10, Y: 1, Z: 0},
+	}
+	if len(got.Vertices) != len(want) {
+		t.Fatalf("unexpected vertex count: got:%d want:%d", len(got.Vertices), len(want))
+	}
+	for i, v := range want {
+		if got.Vertices[i] != v {
+			t.Errorf("unexpected vertex %d: got:%+v want:%+v", i, got.Vertices[i], v)
+		}
+	}
+}
+
+// TestDecodeOutOfRangeVertex checks that a triangle referencing a
+// vertex index beyond the object's vertex list is rejected with an
+// error rather than panicking, since a 3MF archive is untrusted input.
+func TestDecodeOutOfRangeVertex(t *testing.T) {
+	const modelXML = xmlHeader +
+		`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" unit="millimeter">` +
+		`<resources>` +
+		`<object id="1" type="model">` +
+		`<mesh>` +
+		`<vertices>` +
+		`<vertex x="0" y="0" z="0"/>` +
+		`<vertex x="1" y="0" z="0"/>` +
+		`<vertex x="0" y="1" z="0"/>` +
+		`</vertices>` +
+		`<triangles><triangle v1="0" v2="1" v3="99"/></triangles>` +
+		`</mesh>` +
+		`</object>` +
+		`</resources>` +
+		`<build><item objectid="1"/></build>` +
+		`</model>`
+
+	archive := archiveWithModel(t, modelXML)
+	_, err := Decode(bytes.NewReader(archive), int64(len(archive)))
+	if err == nil {
+		t.Fatal("expected an error for an out-of-range vertex index")
+	}
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>`
+
+// archiveWithModel returns a minimal 3MF archive containing modelXML as
+// its 3D/3dmodel.model part.
+func archiveWithModel(t *testing.T, modelXML string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(modelPart)
+	if err != nil {
+		t.Fatalf("failed to create archive part: %v", err)
+	}
+	_, err = io.WriteString(f, modelXML)
+	if err != nil {
+		t.Fatalf("failed to write archive part: %v", err)
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,153 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import "math"
+
+// Matrix4 is a row-major 4×4 affine transformation matrix.
+type Matrix4 [4][4]float64
+
+// Identity4 is the identity Matrix4.
+var Identity4 = Matrix4{
+	{1, 0, 0, 0},
+	{0, 1, 0, 0},
+	{0, 0, 1, 0},
+	{0, 0, 0, 1},
+}
+
+// apply applies m to v as an affine transform.
+func (m Matrix4) apply(v Vector) Vector {
+	return Vector{
+		X: m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z + m[0][3],
+		Y: m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z + m[1][3],
+		Z: m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z + m[2][3],
+	}
+}
+
+// det3 returns the determinant of the upper-left 3×3 linear part of m,
+// used to detect orientation-reversing transforms.
+func (m Matrix4) det3() float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// Scale returns a Matrix4 that scales uniformly by f about the origin.
+func Scale(f float64) Matrix4 {
+	m := Identity4
+	m[0][0], m[1][1], m[2][2] = f, f, f
+	return m
+}
+
+// Translate returns a Matrix4 that translates by v.
+func Translate(v Vector) Matrix4 {
+	m := Identity4
+	m[0][3], m[1][3], m[2][3] = v.X, v.Y, v.Z
+	return m
+}
+
+// Rotate returns a Matrix4 that rotates by angle radians, using the
+// right-hand rule, about axis. axis need not be normalized.
+func Rotate(axis Vector, angle float64) Matrix4 {
+	n := axis.scale(1 / axis.length())
+	s, c := math.Sin(angle), math.Cos(angle)
+	t := 1 - c
+	m := Identity4
+	m[0][0], m[0][1], m[0][2] = t*n.X*n.X+c, t*n.X*n.Y-s*n.Z, t*n.X*n.Z+s*n.Y
+	m[1][0], m[1][1], m[1][2] = t*n.X*n.Y+s*n.Z, t*n.Y*n.Y+c, t*n.Y*n.Z-s*n.X
+	m[2][0], m[2][1], m[2][2] = t*n.X*n.Z-s*n.Y, t*n.Y*n.Z+s*n.X, t*n.Z*n.Z+c
+	return m
+}
+
+// Unit is a length unit used by ConvertUnits. An STL stream carries no
+// unit information of its own; Unit lets a caller state what the
+// vertex coordinates in a stream actually represent.
+type Unit int
+
+const (
+	Millimeter Unit = iota
+	Centimeter
+	Meter
+	Micron
+	Inch
+	Foot
+)
+
+// unitToMillimeter gives the scale factor from a Unit to millimeters.
+var unitToMillimeter = map[Unit]float64{
+	Millimeter: 1,
+	Centimeter: 10,
+	Meter:      1000,
+	Micron:     0.001,
+	Inch:       25.4,
+	Foot:       304.8,
+}
+
+// ConvertUnits returns a Matrix4 that scales coordinates expressed in
+// from into to.
+func ConvertUnits(from, to Unit) Matrix4 {
+	return Scale(unitToMillimeter[from] / unitToMillimeter[to])
+}
+
+// transformDecoder wraps a Decoder, applying an affine transform to
+// every triangle as it is decoded.
+type transformDecoder struct {
+	Decoder
+	m    Matrix4
+	flip bool
+}
+
+// Transform wraps dec, applying the affine transform m to every vertex
+// and recomputing Normal from the transformed triangle as it is
+// decoded. If m reverses orientation (negative determinant), the
+// recomputed normal is negated so that it continues to point outward.
+// This allows streaming pipelines, such as decode binary, scale
+// millimeters to inches, and re-encode ASCII, without materializing
+// the whole model in memory.
+func Transform(dec Decoder, m Matrix4) Decoder {
+	return &transformDecoder{Decoder: dec, m: m, flip: m.det3() < 0}
+}
+
+func (d *transformDecoder) Decode() (Triangle, error) {
+	t, err := d.Decoder.Decode()
+	if err != nil {
+		return Triangle{}, err
+	}
+	t.Vertex[0] = d.m.apply(t.Vertex[0])
+	t.Vertex[1] = d.m.apply(t.Vertex[1])
+	t.Vertex[2] = d.m.apply(t.Vertex[2])
+	t.Normal = t.FacetNormal()
+	if d.flip {
+		t.Normal = t.Normal.scale(-1)
+	}
+	return t, nil
+}
+
+// TransformEncoder wraps an Encoder, applying an affine transform to
+// every triangle before it is encoded.
+type TransformEncoder struct {
+	enc  Encoder
+	m    Matrix4
+	flip bool
+}
+
+// NewTransformEncoder returns a TransformEncoder that applies m to
+// every triangle passed to Encode before forwarding it to enc.
+func NewTransformEncoder(enc Encoder, m Matrix4) *TransformEncoder {
+	return &TransformEncoder{enc: enc, m: m, flip: m.det3() < 0}
+}
+
+// Encode transforms t by the wrapped Matrix4 and encodes the result
+// with the wrapped Encoder.
+func (enc *TransformEncoder) Encode(t Triangle) error {
+	t.Vertex[0] = enc.m.apply(t.Vertex[0])
+	t.Vertex[1] = enc.m.apply(t.Vertex[1])
+	t.Vertex[2] = enc.m.apply(t.Vertex[2])
+	t.Normal = t.FacetNormal()
+	if enc.flip {
+		t.Normal = t.Normal.scale(-1)
+	}
+	return enc.enc.Encode(t)
+}
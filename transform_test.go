@@ -0,0 +1,161 @@
+// Copyright ©2017 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stl
+
+import (
+	"io"
+	"math"
+	"testing"
+)
+
+// sliceDecoder is a minimal Decoder backed by a slice, used to test
+// decoder wrappers without needing an STL stream.
+type sliceDecoder struct {
+	tris []Triangle
+	i    int
+}
+
+func (d *sliceDecoder) Decode() (Triangle, error) {
+	if d.i == len(d.tris) {
+		return Triangle{}, io.EOF
+	}
+	t := d.tris[d.i]
+	d.i++
+	return t, nil
+}
+
+func (d *sliceDecoder) Name() string      { return "" }
+func (d *sliceDecoder) Header() string    { return "" }
+func (d *sliceDecoder) NumTriangles() int { return len(d.tris) }
+
+// sliceEncoder is a minimal Encoder that records every Triangle passed
+// to it, used to test encoder wrappers without needing an STL stream.
+type sliceEncoder struct {
+	tris []Triangle
+}
+
+func (e *sliceEncoder) Encode(t Triangle) error {
+	e.tris = append(e.tris, t)
+	return nil
+}
+
+func TestTransformScale(t *testing.T) {
+	src := &sliceDecoder{tris: []Triangle{
+		{
+			Normal: Vector{0, 0, 1},
+			Vertex: [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		},
+	}}
+	dec := Transform(src, Scale(2))
+
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	want := [3]Vector{{0, 0, 0}, {2, 0, 0}, {0, 2, 0}}
+	if got.Vertex != want {
+		t.Errorf("unexpected scaled vertices: got:%+v want:%+v", got.Vertex, want)
+	}
+	if !sameVector(got.Normal, Vector{0, 0, 1}, 1e-14) {
+		t.Errorf("unexpected scaled normal: got:%+v want:{0 0 1}", got.Normal)
+	}
+
+	_, err = dec.Decode()
+	if err != io.EOF {
+		t.Errorf("unexpected error at end of stream: got:%v want:%v", err, io.EOF)
+	}
+}
+
+func TestTransformReflect(t *testing.T) {
+	src := &sliceDecoder{tris: []Triangle{
+		{
+			Normal: Vector{0, 0, 1},
+			Vertex: [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		},
+	}}
+	// Mirror across the YZ plane: this reverses orientation, so the
+	// recomputed normal must be negated to continue to point outward.
+	reflect := Matrix4{
+		{-1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+	dec := Transform(src, reflect)
+
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	want := [3]Vector{{0, 0, 0}, {-1, 0, 0}, {0, 1, 0}}
+	if got.Vertex != want {
+		t.Errorf("unexpected reflected vertices: got:%+v want:%+v", got.Vertex, want)
+	}
+	if !sameVector(got.Normal, Vector{0, 0, 1}, 1e-14) {
+		t.Errorf("reflected normal was not flipped back to outward-facing: got:%+v want:{0 0 1}", got.Normal)
+	}
+}
+
+func TestTransformEncoder(t *testing.T) {
+	dst := &sliceEncoder{}
+	enc := NewTransformEncoder(dst, Scale(2))
+
+	err := enc.Encode(Triangle{
+		Normal: Vector{0, 0, 1},
+		Vertex: [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	if len(dst.tris) != 1 {
+		t.Fatalf("unexpected encoded triangle count: got:%d want:1", len(dst.tris))
+	}
+	want := [3]Vector{{0, 0, 0}, {2, 0, 0}, {0, 2, 0}}
+	if dst.tris[0].Vertex != want {
+		t.Errorf("unexpected scaled vertices: got:%+v want:%+v", dst.tris[0].Vertex, want)
+	}
+	if !sameVector(dst.tris[0].Normal, Vector{0, 0, 1}, 1e-14) {
+		t.Errorf("unexpected scaled normal: got:%+v want:{0 0 1}", dst.tris[0].Normal)
+	}
+}
+
+func TestTransformEncoderReflect(t *testing.T) {
+	dst := &sliceEncoder{}
+	reflect := Matrix4{
+		{-1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+	enc := NewTransformEncoder(dst, reflect)
+
+	err := enc.Encode(Triangle{
+		Normal: Vector{0, 0, 1},
+		Vertex: [3]Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	if !sameVector(dst.tris[0].Normal, Vector{0, 0, 1}, 1e-14) {
+		t.Errorf("reflected normal was not flipped back to outward-facing: got:%+v want:{0 0 1}", dst.tris[0].Normal)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	m := Rotate(Vector{0, 0, 1}, math.Pi/2)
+	got := m.apply(Vector{X: 1})
+	want := Vector{Y: 1}
+	if !sameVector(got, want, 1e-9) {
+		t.Errorf("unexpected rotation: got:%+v want:%+v", got, want)
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	m := ConvertUnits(Inch, Millimeter)
+	got := m.apply(Vector{X: 1})
+	if !sameFloat64(got.X, 25.4, 1e-9) {
+		t.Errorf("unexpected unit conversion: got:%v want:25.4", got.X)
+	}
+}